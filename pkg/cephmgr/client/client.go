@@ -0,0 +1,29 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client talks to a Ceph cluster over a librados connection.
+package client
+
+// Connection represents an open connection to a Ceph cluster.
+type Connection interface {
+	MonCommand(args []byte) (buffer []byte, info string, err error)
+	Shutdown()
+}
+
+// ConnectionFactory creates new connections to a Ceph cluster.
+type ConnectionFactory interface {
+	NewConnWithClusterAndUser(clusterName, user string) (Connection, error)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package test provides mock implementations of the cephmgr/client interfaces.
+package test
+
+import "github.com/rook/rook/pkg/cephmgr/client"
+
+// MockConnectionFactory is a test double for client.ConnectionFactory.
+type MockConnectionFactory struct {
+	MockNewConnWithClusterAndUser func(clusterName, user string) (client.Connection, error)
+}
+
+func (f *MockConnectionFactory) NewConnWithClusterAndUser(clusterName, user string) (client.Connection, error) {
+	if f.MockNewConnWithClusterAndUser != nil {
+		return f.MockNewConnWithClusterAndUser(clusterName, user)
+	}
+	return &MockConnection{}, nil
+}
+
+// MockConnection is a test double for client.Connection.
+type MockConnection struct {
+	MockMonCommand func(args []byte) (buffer []byte, info string, err error)
+}
+
+func (c *MockConnection) MonCommand(args []byte) (buffer []byte, info string, err error) {
+	if c.MockMonCommand != nil {
+		return c.MockMonCommand(args)
+	}
+	return nil, "", nil
+}
+
+func (c *MockConnection) Shutdown() {}
@@ -0,0 +1,388 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package osd manages the lifecycle of the OSDs running on a node: bootstrapping
+// their keyring, partitioning the devices that back them, and tracking the
+// resulting layout in etcd.
+package osd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/google/uuid"
+	"github.com/rook/rook/pkg/cephmgr/client"
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "cephmgr-osd")
+
+// osdConfig is the on-disk and in-etcd state for a single OSD being set up
+// on this node.
+type osdConfig struct {
+	configRoot      string
+	rootPath        string
+	id              int
+	uuid            uuid.UUID
+	dir             bool
+	partitionScheme *partition.PerfSchemeEntry
+}
+
+// loadOSDInfo reads the id and fsid that ceph-osd wrote out under the OSD's
+// data directory the first time it was bootstrapped.
+func loadOSDInfo(config *osdConfig) error {
+	idFile := filepath.Join(config.rootPath, "whoami")
+	idContent, err := ioutil.ReadFile(idFile)
+	if err != nil {
+		return fmt.Errorf("failed to read osd id from %s: %+v", idFile, err)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(idContent)))
+	if err != nil {
+		return fmt.Errorf("invalid osd id in %s: %+v", idFile, err)
+	}
+
+	fsidFile := filepath.Join(config.rootPath, "fsid")
+	fsidContent, err := ioutil.ReadFile(fsidFile)
+	if err != nil {
+		return fmt.Errorf("failed to read osd fsid from %s: %+v", fsidFile, err)
+	}
+	fsid, err := uuid.Parse(strings.TrimSpace(string(fsidContent)))
+	if err != nil {
+		return fmt.Errorf("invalid osd fsid in %s: %+v", fsidFile, err)
+	}
+
+	config.id = id
+	config.uuid = fsid
+	return nil
+}
+
+// getBootstrapOSDKeyringPath returns the path where the bootstrap-osd keyring
+// for the given cluster is cached on this node.
+func getBootstrapOSDKeyringPath(configDir, clusterName string) string {
+	return filepath.Join(configDir, fmt.Sprintf("bootstrap-osd-%s.keyring", clusterName))
+}
+
+// createOSDBootstrapKeyring requests a bootstrap-osd key from the mon and
+// writes it out to the path returned by getBootstrapOSDKeyringPath.
+func createOSDBootstrapKeyring(conn client.Connection, configDir, clusterName string) error {
+	args := map[string]interface{}{
+		"prefix": "auth get-or-create-key",
+		"entity": "client.bootstrap-osd",
+		"caps":   []string{"mon", "allow profile bootstrap-osd"},
+		"format": "json",
+	}
+	buf, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mon command: %+v", err)
+	}
+
+	response, _, err := conn.MonCommand(buf)
+	if err != nil {
+		return fmt.Errorf("failed to get bootstrap-osd key: %+v", err)
+	}
+
+	var result struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal bootstrap-osd key response: %+v", err)
+	}
+
+	keyring := fmt.Sprintf(
+		`[client.bootstrap-osd]
+	key = %s
+	caps mon = "allow profile bootstrap-osd"
+`, result.Key)
+
+	keyringPath := getBootstrapOSDKeyringPath(configDir, clusterName)
+	if err := ioutil.WriteFile(keyringPath, []byte(keyring), 0644); err != nil {
+		return fmt.Errorf("failed to write bootstrap-osd keyring to %s: %+v", keyringPath, err)
+	}
+	return nil
+}
+
+// rookOwnsPartitions returns true if every partition on the device is known
+// to be rook's. Each partition is first looked up by its PARTUUID in the
+// node-agnostic identity index (isRookOwnedByIdentity); a partition that isn't
+// found there is still accepted when its PARTLABEL starts with "ROOK-OSD". That
+// label check can't tell a pre-migration rook partition from any other
+// partition carrying rook's naming convention, and it also covers partitions
+// that are never indexed by PARTUUID: the WAL/DB partitions, and encrypted
+// partitions, whose records are keyed by their LUKS2 UUID. Callers run
+// migrateLegacyLabelOwnership first so that partitions with legacy
+// label-keyed records get indexed by PARTUUID.
+func rookOwnsPartitions(context *clusterd.Context, partitions []*sys.Partition) bool {
+	if len(partitions) == 0 {
+		return false
+	}
+	for _, p := range partitions {
+		if isRookOwnedByIdentity(context, p) {
+			continue
+		}
+		if !strings.HasPrefix(p.PartLabel, "ROOK-OSD") {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDeviceFormattable runs the pre-flight checks formatDevice and
+// formatDistributedDataDevice share: that any partitions already on device
+// are rook's own, and that device doesn't already carry a foreign filesystem.
+func checkDeviceFormattable(context *clusterd.Context, device string, forceFormat bool) error {
+	partitions, _, err := sys.GetDevicePartitions(device, context.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get partitions for %s: %+v", device, err)
+	}
+	if err := migrateLegacyLabelOwnership(context, partitions); err != nil {
+		return err
+	}
+
+	if len(partitions) > 0 && !rookOwnsPartitions(context, partitions) && !forceFormat {
+		return fmt.Errorf("device %s has partitions rook does not own; not formatting", device)
+	}
+
+	fsOutput, err := context.Executor.ExecuteCommandWithOutput("df", "df", fmt.Sprintf("/dev/%s", device))
+	if err != nil {
+		return fmt.Errorf("failed to check filesystem on %s: %+v", device, err)
+	}
+	if strings.TrimSpace(fsOutput) != "" && !rookOwnsPartitions(context, partitions) && !forceFormat {
+		return fmt.Errorf("device %s already has a filesystem; not formatting", device)
+	}
+	return nil
+}
+
+// formatDevice partitions and formats the device backing config's bluestore
+// OSD. If the device already has partitions that rook doesn't own, it refuses
+// unless forceFormat is set.
+func formatDevice(context *clusterd.Context, config *osdConfig, forceFormat bool) error {
+	device := config.partitionScheme.BlockPartition.Device
+	if err := checkDeviceFormattable(context, device, forceFormat); err != nil {
+		return err
+	}
+	return partitionBluestoreOSD(context, config)
+}
+
+// formatDistributedDataDevice partitions and formats just the data disk of an
+// OSD using a distributed layout, where the WAL and DB partitions live on a
+// separate shared metadata device that partitionBluestoreMetadata lays out
+// instead. It shares formatDevice's pre-flight ownership/filesystem checks.
+func formatDistributedDataDevice(context *clusterd.Context, config *osdConfig, forceFormat bool) error {
+	device := config.partitionScheme.BlockPartition.Device
+	if err := checkDeviceFormattable(context, device, forceFormat); err != nil {
+		return err
+	}
+	return partitionBluestoreOSDData(context, config)
+}
+
+// partitionBluestoreOSD zaps the OSD's device and lays out its WAL, DB and
+// BLOCK partitions collocated on it, encrypting each with LUKS2 first when
+// requested. For a distributed layout, where the WAL/DB live on a separate
+// metadata device, use partitionBluestoreOSDData and partitionBluestoreMetadata
+// instead.
+func partitionBluestoreOSD(context *clusterd.Context, config *osdConfig) error {
+	scheme := config.partitionScheme
+	device := scheme.BlockPartition.Device
+	partitioner := getPartitioner(context)
+
+	if err := partitioner.Zap(device); err != nil {
+		return err
+	}
+
+	parts := []*partition.PerfSchemePartitionDetails{scheme.WalPartition, scheme.DatabasePartition, scheme.BlockPartition}
+	if err := createPartitions(partitioner, device, scheme.ID, parts); err != nil {
+		return err
+	}
+
+	for _, p := range parts {
+		if err := encryptPartitionIfNeeded(context, config.id, p); err != nil {
+			return err
+		}
+	}
+
+	blockDetails, err := getBlockPartitionDetails(config)
+	if err != nil {
+		return err
+	}
+
+	// data and metadata are collocated on the same device for this scheme
+	if err := recordOSDOnDevice(context, blockDetails.DiskUUID, "osd-id-data", config.id); err != nil {
+		return err
+	}
+	return recordOSDOnDevice(context, blockDetails.DiskUUID, "osd-id-metadata", config.id)
+}
+
+// partitionBluestoreOSDData zaps config's data disk and lays out only its
+// BLOCK partition, encrypting it first when requested. Used for a distributed
+// layout, where the WAL and DB partitions live on a separate shared metadata
+// device that partitionBluestoreMetadata partitions instead.
+func partitionBluestoreOSDData(context *clusterd.Context, config *osdConfig) error {
+	scheme := config.partitionScheme
+	device := scheme.BlockPartition.Device
+	partitioner := getPartitioner(context)
+
+	if err := partitioner.Zap(device); err != nil {
+		return err
+	}
+
+	parts := []*partition.PerfSchemePartitionDetails{scheme.BlockPartition}
+	if err := createPartitions(partitioner, device, scheme.ID, parts); err != nil {
+		return err
+	}
+
+	if err := encryptPartitionIfNeeded(context, config.id, scheme.BlockPartition); err != nil {
+		return err
+	}
+
+	blockDetails, err := getBlockPartitionDetails(config)
+	if err != nil {
+		return err
+	}
+	return recordOSDOnDevice(context, blockDetails.DiskUUID, "osd-id-data", config.id)
+}
+
+// partitionBluestoreMetadata partitions a shared metadata device for all of
+// the WAL/DB partitions that have been assigned to it, and records each OSD
+// that depends on the device in etcd.
+func partitionBluestoreMetadata(context *clusterd.Context, metadata *partition.MetadataDeviceInfo, configDir string) error {
+	partitioner := getPartitioner(context)
+	if err := partitioner.Zap(metadata.Device); err != nil {
+		return err
+	}
+
+	specs := make([]partition.PartitionSpec, 0, len(metadata.Partitions))
+	for _, p := range metadata.Partitions {
+		specs = append(specs, partition.PartitionSpec{
+			Number:   p.Number,
+			Label:    fmt.Sprintf("ROOK-OSD%d-%s", p.OsdID, strings.ToUpper(p.Type)),
+			SizeMB:   p.SizeMB,
+			TypeGUID: partition.BluestoreTypeGUID,
+		})
+	}
+	if err := partitioner.CreatePartitions(metadata.Device, specs); err != nil {
+		return err
+	}
+
+	ids := []string{}
+	for _, id := range metadata.OsdIDs() {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	value := strings.Join(ids, ",")
+	if err := context.EtcdClient.SetValue(
+		fmt.Sprintf("/rook/services/ceph/osd/desired/%s/device/%s/osd-id-metadata", context.NodeID, metadata.DiskUUID),
+		value); err != nil {
+		return err
+	}
+	return recordDeviceIdentity(context, metadata.DiskUUID, "osd-id-metadata", value)
+}
+
+// getPartitioner returns context's configured DiskPartitioner, defaulting to
+// the sgdisk-backed implementation when none was set.
+func getPartitioner(context *clusterd.Context) partition.DiskPartitioner {
+	if context.Partitioner != nil {
+		return context.Partitioner
+	}
+	return &partition.SgdiskPartitioner{Executor: context.Executor}
+}
+
+func createPartitions(partitioner partition.DiskPartitioner, device string, osdID int, parts []*partition.PerfSchemePartitionDetails) error {
+	specs := make([]partition.PartitionSpec, len(parts))
+	labels := map[string]*partition.PerfSchemePartitionDetails{}
+	for i, p := range parts {
+		label := fmt.Sprintf("ROOK-OSD%d-%s", osdID, strings.ToUpper(p.Type))
+		specs[i] = partition.PartitionSpec{Number: p.Number, Label: label, SizeMB: p.SizeMB, TypeGUID: partition.BluestoreTypeGUID}
+		labels[label] = p
+	}
+
+	if err := partitioner.CreatePartitions(device, specs); err != nil {
+		return err
+	}
+
+	assignPartitionUUIDs(partitioner, device, labels)
+	return nil
+}
+
+// assignPartitionUUIDs re-reads device's partition table and records each
+// partition's real PARTUUID on the matching PerfSchemePartitionDetails. The
+// PARTUUID is what identifies the partition as rook-owned from now on;
+// PARTLABEL is kept only as a human-readable hint. If the partition table
+// can't be read back (e.g. a test double that doesn't model PARTUUID), a
+// freshly generated id is used instead so callers always have something to
+// key etcd records off of.
+func assignPartitionUUIDs(partitioner partition.DiskPartitioner, device string, labels map[string]*partition.PerfSchemePartitionDetails) {
+	layout, err := partitioner.ReadLayout(device)
+	if err != nil {
+		logger.Warningf("failed to re-read partitions on %s to record PARTUUIDs: %+v", device, err)
+	}
+	for _, spec := range layout {
+		if p, ok := labels[spec.Label]; ok && spec.UUID != "" {
+			if id, err := uuid.Parse(spec.UUID); err == nil {
+				p.DiskUUID = id
+			}
+		}
+	}
+	for _, p := range labels {
+		if p.DiskUUID == uuid.Nil {
+			p.DiskUUID = uuid.Must(uuid.NewRandom())
+		}
+	}
+}
+
+// getBlockPartitionDetails returns config's BLOCK (data) partition. Everything
+// downstream keys off BlockPartition.DiskUUID - the partition's PARTUUID, or
+// its LUKS2 UUID once encrypted - never its PARTLABEL, so this also guards
+// against returning a partition whose identity hasn't actually been resolved.
+func getBlockPartitionDetails(config *osdConfig) (*partition.PerfSchemePartitionDetails, error) {
+	if config.partitionScheme == nil || config.partitionScheme.BlockPartition == nil {
+		return nil, fmt.Errorf("no block partition recorded for osd %d", config.id)
+	}
+	block := config.partitionScheme.BlockPartition
+	if block.DiskUUID == uuid.Nil {
+		return nil, fmt.Errorf("block partition for osd %d has no identity uuid recorded", config.id)
+	}
+	return block, nil
+}
+
+// recordOSDOnDevice stores which OSD owns the given etcd key (osd-id-data or
+// osd-id-metadata) for a device, keyed by the device's stable UUID. It also
+// mirrors the record under the node-agnostic uuid index (see identity.go) so
+// the OSD is still recognized if the disk is later moved to another node.
+func recordOSDOnDevice(context *clusterd.Context, diskUUID uuid.UUID, key string, osdID int) error {
+	value := strconv.Itoa(osdID)
+	if err := context.EtcdClient.SetValue(
+		fmt.Sprintf("/rook/services/ceph/osd/desired/%s/device/%s/%s", context.NodeID, diskUUID, key), value); err != nil {
+		return err
+	}
+	return recordDeviceIdentity(context, diskUUID, key, value)
+}
+
+// recordDeviceSize updates the recorded size in bytes for the device with the
+// given UUID, e.g. after growBluestoreOSD expands its BLOCK partition.
+func recordDeviceSize(context *clusterd.Context, diskUUID uuid.UUID, sizeBytes uint64) error {
+	value := strconv.FormatUint(sizeBytes, 10)
+	if err := context.EtcdClient.SetValue(
+		fmt.Sprintf("/rook/services/ceph/osd/desired/%s/device/%s/size", context.NodeID, diskUUID), value); err != nil {
+		return err
+	}
+	return recordDeviceIdentity(context, diskUUID, "size", value)
+}
@@ -91,30 +91,9 @@ func TestOverwriteRookOwnedPartitions(t *testing.T) {
 	nodeID := "node123"
 	etcdClient := util.NewMockEtcdClient()
 
-	// set up mock execute so we can verify the partitioning happens on sda
-	execCount := 0
-	executor := &exectest.MockExecutor{}
-	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
-		logger.Infof("RUN %d for '%s'. %s %+v", execCount, name, command, args)
-		assert.Equal(t, "sgdisk", command)
-		switch execCount {
-		case 0:
-			assert.Equal(t, []string{"--zap-all", "/dev/sda"}, args)
-		case 1:
-			assert.Equal(t, []string{"--clear", "--mbrtogpt", "/dev/sda"}, args)
-		case 2:
-			assert.Equal(t, 11, len(args))
-			assert.Equal(t, "--change-name=1:ROOK-OSD1-WAL", args[1])
-			assert.Equal(t, "--change-name=2:ROOK-OSD1-DB", args[4])
-			assert.Equal(t, "--change-name=3:ROOK-OSD1-BLOCK", args[7])
-			assert.Equal(t, "/dev/sda", args[10])
-		}
-		execCount++
-		return nil
-	}
-
 	// set up a mock function to return "rook owned" partitions on the device and it does not have a filesystem
 	outputExecCount := 0
+	executor := &exectest.MockExecutor{}
 	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
 		logger.Infof("OUTPUT %d for %s. %s %+v", outputExecCount, name, command, args)
 		var output string
@@ -139,8 +118,9 @@ NAME="sda3" SIZE="20" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD0-BLOCK"`
 	entry.OsdUUID = uuid.Must(uuid.NewRandom())
 	partition.PopulateCollocatedPerfSchemeEntry(entry, "sda", partition.BluestoreConfig{})
 
+	parsedPartitioner := partition.NewParsedPartitioner()
 	context := &clusterd.Context{EtcdClient: etcdClient, Executor: executor, NodeID: nodeID,
-		ConfigDir: configDir, Inventory: createInventory()}
+		ConfigDir: configDir, Inventory: createInventory(), Partitioner: parsedPartitioner}
 	context.Inventory.Local.Disks = []*inventory.LocalDisk{
 		&inventory.LocalDisk{Name: "sda", Size: 65},
 	}
@@ -150,14 +130,22 @@ NAME="sda3" SIZE="20" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD0-BLOCK"`
 	// ensure that our mocking makes it look like rook owns the partitions on sda
 	partitions, _, err := sys.GetDevicePartitions("sda", context.Executor)
 	assert.Nil(t, err)
-	assert.True(t, rookOwnsPartitions(partitions))
+	assert.True(t, rookOwnsPartitions(context, partitions))
 
 	// try to format the device.  even though the device has existing partitions, they are owned by rook, so it is safe
 	// to format and the format/partitioning will happen.
 	err = formatDevice(context, config, false)
 	assert.Nil(t, err)
-	assert.Equal(t, 3, execCount)
 	assert.Equal(t, 3, outputExecCount)
+
+	// the partitioner should have zapped sda and laid out the WAL/DB/BLOCK partitions, in terms of
+	// structured specs rather than raw sgdisk argv
+	assert.Equal(t, []string{"sda"}, parsedPartitioner.ZappedDevices)
+	specs := parsedPartitioner.CreatedSpecs["sda"]
+	assert.Equal(t, 3, len(specs))
+	assert.Equal(t, "ROOK-OSD1-WAL", specs[0].Label)
+	assert.Equal(t, "ROOK-OSD1-DB", specs[1].Label)
+	assert.Equal(t, "ROOK-OSD1-BLOCK", specs[2].Label)
 }
 
 func TestPartitionBluestoreMetadata(t *testing.T) {
@@ -171,28 +159,8 @@ func TestPartitionBluestoreMetadata(t *testing.T) {
 	nodeID := "node123"
 	etcdClient := util.NewMockEtcdClient()
 
-	execCount := 0
-	executor := &exectest.MockExecutor{}
-	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
-		logger.Infof("RUN %d for '%s'. %s %+v", execCount, name, command, args)
-		assert.Equal(t, "sgdisk", command)
-		switch execCount {
-		case 0:
-			assert.Equal(t, []string{"--zap-all", "/dev/sda"}, args)
-		case 1:
-			assert.Equal(t, []string{"--clear", "--mbrtogpt", "/dev/sda"}, args)
-		case 2:
-			assert.Equal(t, 14, len(args))
-			assert.Equal(t, "--change-name=1:ROOK-OSD1-WAL", args[1])
-			assert.Equal(t, "--change-name=2:ROOK-OSD1-DB", args[4])
-			assert.Equal(t, "--change-name=3:ROOK-OSD2-WAL", args[7])
-			assert.Equal(t, "--change-name=4:ROOK-OSD2-DB", args[10])
-		}
-		execCount++
-		return nil
-	}
-
-	context := &clusterd.Context{EtcdClient: etcdClient, Executor: executor, NodeID: nodeID, ConfigDir: configDir}
+	parsedPartitioner := partition.NewParsedPartitioner()
+	context := &clusterd.Context{EtcdClient: etcdClient, NodeID: nodeID, ConfigDir: configDir, Partitioner: parsedPartitioner}
 
 	// create metadata partition information for 2 OSDs (sdb, sdc) storing their metadata on device sda
 	bluestoreConfig := partition.BluestoreConfig{WalSizeMB: 1, DatabaseSizeMB: 2}
@@ -211,7 +179,16 @@ func TestPartitionBluestoreMetadata(t *testing.T) {
 	// perform the metadata device partition
 	err = partitionBluestoreMetadata(context, metadata, configDir)
 	assert.Nil(t, err)
-	assert.Equal(t, 3, execCount)
+
+	// the partitioner should have zapped sda and laid out the 4 WAL/DB partitions for osds 1 and 2,
+	// in terms of structured specs rather than raw sgdisk argv
+	assert.Equal(t, []string{"sda"}, parsedPartitioner.ZappedDevices)
+	specs := parsedPartitioner.CreatedSpecs["sda"]
+	assert.Equal(t, 4, len(specs))
+	assert.Equal(t, "ROOK-OSD1-WAL", specs[0].Label)
+	assert.Equal(t, "ROOK-OSD1-DB", specs[1].Label)
+	assert.Equal(t, "ROOK-OSD2-WAL", specs[2].Label)
+	assert.Equal(t, "ROOK-OSD2-DB", specs[3].Label)
 
 	// verify that the metadata device has been associated with the OSDs that are storing their metadata on it,
 	// e.g. OSDs 1 and 2
@@ -232,29 +209,10 @@ func TestPartitionBluestoreOSD(t *testing.T) {
 	nodeID := "node123"
 	etcdClient := util.NewMockEtcdClient()
 
-	// setup the mock executor to validate the calls to partition the device
-	execCount := 0
-	executor := &exectest.MockExecutor{}
-	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
-		logger.Infof("RUN %d for '%s'. %s %+v", execCount, name, command, args)
-		assert.Equal(t, "sgdisk", command)
-		switch execCount {
-		case 0:
-			assert.Equal(t, []string{"--zap-all", "/dev/sda"}, args)
-		case 1:
-			assert.Equal(t, []string{"--clear", "--mbrtogpt", "/dev/sda"}, args)
-		case 2:
-			assert.Equal(t, 11, len(args))
-			assert.Equal(t, "--change-name=1:ROOK-OSD1-WAL", args[1])
-			assert.Equal(t, "--change-name=2:ROOK-OSD1-DB", args[4])
-			assert.Equal(t, "--change-name=3:ROOK-OSD1-BLOCK", args[7])
-		}
-		execCount++
-		return nil
-	}
-
-	// setup a context with 1 disk: sda
-	context := &clusterd.Context{EtcdClient: etcdClient, Executor: executor, NodeID: nodeID, ConfigDir: configDir, Inventory: createInventory()}
+	// setup a context with 1 disk: sda, and a partitioner that records structured partition specs
+	parsedPartitioner := partition.NewParsedPartitioner()
+	context := &clusterd.Context{EtcdClient: etcdClient, NodeID: nodeID, ConfigDir: configDir,
+		Inventory: createInventory(), Partitioner: parsedPartitioner}
 	context.Inventory.Local.Disks = []*inventory.LocalDisk{
 		&inventory.LocalDisk{Name: "sda", Size: 100},
 	}
@@ -272,7 +230,15 @@ func TestPartitionBluestoreOSD(t *testing.T) {
 	// partition the OSD on sda now
 	err = partitionBluestoreOSD(context, config)
 	assert.Nil(t, err)
-	assert.Equal(t, 3, execCount)
+
+	// the partitioner should have zapped sda and laid out the WAL/DB/BLOCK partitions, in terms of
+	// structured specs rather than raw sgdisk argv
+	assert.Equal(t, []string{"sda"}, parsedPartitioner.ZappedDevices)
+	specs := parsedPartitioner.CreatedSpecs["sda"]
+	assert.Equal(t, 3, len(specs))
+	assert.Equal(t, "ROOK-OSD1-WAL", specs[0].Label)
+	assert.Equal(t, "ROOK-OSD1-DB", specs[1].Label)
+	assert.Equal(t, "ROOK-OSD1-BLOCK", specs[2].Label)
 
 	// verify that both the data and metadata have been associated with the device in etcd (since data/metadata are collocated)
 	blockDetails, err := getBlockPartitionDetails(config)
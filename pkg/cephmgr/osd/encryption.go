@@ -0,0 +1,114 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+// encryptPartitionIfNeeded wraps p's partition in a LUKS2 container and opens
+// it, so that subsequent steps point Ceph at /dev/mapper/<mapperName> instead
+// of the raw partition. If the partition is already a LUKS2 device - e.g. a
+// retried OSD setup re-running against a disk it already formatted - it is
+// unlocked rather than reformatted, so existing data is never destroyed.
+func encryptPartitionIfNeeded(context *clusterd.Context, osdID int, p *partition.PerfSchemePartitionDetails) error {
+	if p.Encryption == nil {
+		return nil
+	}
+
+	partDevice := p.PartitionDevice()
+	p.MapperName = fmt.Sprintf("rook-osd%d-%s", osdID, p.Type)
+
+	keyFile, err := resolveEncryptionKey(context, osdID, p)
+	if err != nil {
+		return err
+	}
+
+	if sys.IsLuks(context.Executor, partDevice) {
+		logger.Infof("%s is already a LUKS2 device; unlocking instead of reformatting", partDevice)
+	} else if err := context.Executor.ExecuteCommand("cryptsetup", "cryptsetup",
+		"luksFormat", "--type", "luks2",
+		"--cipher", p.Encryption.GetCipher(),
+		"--key-size", strconv.Itoa(p.Encryption.GetKeySizeBits()),
+		"--batch-mode", "--key-file", keyFile, partDevice); err != nil {
+		return fmt.Errorf("failed to luksFormat %s: %+v", partDevice, err)
+	}
+
+	if err := context.Executor.ExecuteCommand("cryptsetup", "cryptsetup",
+		"open", "--type", "luks2", "--key-file", keyFile, partDevice, p.MapperName); err != nil {
+		return fmt.Errorf("failed to open luks device %s: %+v", partDevice, err)
+	}
+
+	luksUUID, err := sys.LuksUUID(context.Executor, partDevice)
+	if err != nil {
+		return err
+	}
+	p.LuksUUID = luksUUID
+	// the LUKS2 uuid is what identifies the volume going forward, since the
+	// partition uuid underneath it changes if the volume is ever re-encrypted
+	p.DiskUUID = luksUUID
+
+	return nil
+}
+
+// resolveEncryptionKey returns the path to a key file cryptsetup can be
+// pointed at for p, producing the key material per p.Encryption.KeySource.
+func resolveEncryptionKey(context *clusterd.Context, osdID int, p *partition.PerfSchemePartitionDetails) (string, error) {
+	switch p.Encryption.KeySource {
+	case partition.KeySourceKeyfile:
+		if p.Encryption.KeyfilePath == "" {
+			return "", fmt.Errorf("encryption key source %q requires KeyfilePath to be set", partition.KeySourceKeyfile)
+		}
+		return p.Encryption.KeyfilePath, nil
+
+	case partition.KeySourceRaw, "":
+		// generate the key once and persist it alongside the other local state
+		// rook keeps for this node, so a later open (e.g. after a reboot or a
+		// retried setup) can unlock the same volume
+		path := rawKeyFilePath(context.ConfigDir, osdID, p.Type)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			key := make([]byte, p.Encryption.GetKeySizeBits()/8)
+			if _, err := rand.Read(key); err != nil {
+				return "", fmt.Errorf("failed to generate encryption key for osd %d %s: %+v", osdID, p.Type, err)
+			}
+			if err := ioutil.WriteFile(path, key, 0600); err != nil {
+				return "", fmt.Errorf("failed to persist encryption key to %s: %+v", path, err)
+			}
+		} else if err != nil {
+			return "", fmt.Errorf("failed to check for existing encryption key at %s: %+v", path, err)
+		}
+		return path, nil
+
+	default:
+		return "", fmt.Errorf("encryption key source %q is not yet supported", p.Encryption.KeySource)
+	}
+}
+
+// rawKeyFilePath is where the generated key for a KeySourceRaw partition is
+// stored on this node.
+func rawKeyFilePath(configDir string, osdID int, partType string) string {
+	return filepath.Join(configDir, fmt.Sprintf("osd%d-%s.key", osdID, partType))
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionBluestoreOSDWithEncryption(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "TestPartitionBluestoreOSDWithEncryption")
+	if err != nil {
+		t.Fatalf("failed to create temp config dir: %+v", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	nodeID := "node123"
+	etcdClient := util.NewMockEtcdClient()
+
+	sgdiskCount := 0
+	cryptsetupFormatCount := 0
+	cryptsetupOpenCount := 0
+	keyFilesSeen := map[string]bool{}
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		switch command {
+		case "sgdisk":
+			sgdiskCount++
+		case "cryptsetup":
+			assert.True(t, len(args) > 0)
+			switch args[0] {
+			case "isLuks":
+				// the device has never been formatted yet
+				return fmt.Errorf("not a luks device")
+			case "luksFormat":
+				assert.Equal(t, "--type", args[1])
+				assert.Equal(t, "luks2", args[2])
+				keyFile := requireKeyFileArg(t, args)
+				keyFilesSeen[keyFile] = true
+				contents, err := ioutil.ReadFile(keyFile)
+				assert.Nil(t, err)
+				assert.Equal(t, 512/8, len(contents))
+				cryptsetupFormatCount++
+			case "open":
+				requireKeyFileArg(t, args)
+				cryptsetupOpenCount++
+			}
+		default:
+			t.Fatalf("unexpected command %s", command)
+		}
+		return nil
+	}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		if command == "cryptsetup" {
+			return "11111111-1111-1111-1111-111111111111", nil
+		}
+		return "", nil
+	}
+
+	bluestoreConfig := partition.BluestoreConfig{
+		WalSizeMB:      1,
+		DatabaseSizeMB: 2,
+		Encryption:     &partition.EncryptionConfig{KeySource: partition.KeySourceRaw},
+	}
+	entry := partition.NewPerfSchemeEntry()
+	entry.ID = 1
+	entry.OsdUUID = uuid.Must(uuid.NewRandom())
+	partition.PopulateCollocatedPerfSchemeEntry(entry, "sda", bluestoreConfig)
+
+	context := &clusterd.Context{EtcdClient: etcdClient, Executor: executor, NodeID: nodeID, ConfigDir: configDir}
+	config := &osdConfig{configRoot: configDir, rootPath: filepath.Join(configDir, "osd1"), id: entry.ID,
+		uuid: entry.OsdUUID, dir: false, partitionScheme: entry}
+
+	err = partitionBluestoreOSD(context, config)
+	assert.Nil(t, err)
+
+	// one luksFormat + one open per encrypted partition (wal, db, block)
+	assert.Equal(t, 3, cryptsetupFormatCount)
+	assert.Equal(t, 3, cryptsetupOpenCount)
+
+	// each partition got its own generated raw key, persisted under the config dir
+	assert.Equal(t, 3, len(keyFilesSeen))
+
+	// the LUKS2 uuid, not the partition uuid, is what gets recorded
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", entry.BlockPartition.LuksUUID.String())
+	assert.Equal(t, entry.BlockPartition.LuksUUID, entry.BlockPartition.DiskUUID)
+	assert.Equal(t, "/dev/mapper/rook-osd1-block", entry.BlockPartition.CephDevice())
+}
+
+func TestEncryptPartitionUnlocksExistingLuksDeviceInsteadOfReformatting(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "TestEncryptPartitionUnlocksExistingLuksDevice")
+	if err != nil {
+		t.Fatalf("failed to create temp config dir: %+v", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	cryptsetupFormatCount := 0
+	cryptsetupOpenCount := 0
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		assert.Equal(t, "cryptsetup", command)
+		switch args[0] {
+		case "isLuks":
+			// this partition was already formatted by an earlier, interrupted run
+			return nil
+		case "luksFormat":
+			cryptsetupFormatCount++
+		case "open":
+			requireKeyFileArg(t, args)
+			cryptsetupOpenCount++
+		}
+		return nil
+	}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		return "22222222-2222-2222-2222-222222222222", nil
+	}
+
+	context := &clusterd.Context{EtcdClient: util.NewMockEtcdClient(), Executor: executor, ConfigDir: configDir}
+	p := &partition.PerfSchemePartitionDetails{
+		Type: "block", Device: "sda", Number: 3,
+		Encryption: &partition.EncryptionConfig{KeySource: partition.KeySourceRaw},
+	}
+
+	// the key from the original luksFormat was persisted on this node; a retried
+	// setup should find and reuse it rather than generating a fresh one
+	existingKeyFile := rawKeyFilePath(configDir, 1, p.Type)
+	assert.Nil(t, ioutil.WriteFile(existingKeyFile, []byte("previously-generated-key"), 0600))
+
+	err = encryptPartitionIfNeeded(context, 1, p)
+	assert.Nil(t, err)
+
+	// re-running against an already-luks-formatted partition unlocks it rather than
+	// wiping it with another luksFormat
+	assert.Equal(t, 0, cryptsetupFormatCount)
+	assert.Equal(t, 1, cryptsetupOpenCount)
+	assert.Equal(t, "22222222-2222-2222-2222-222222222222", p.LuksUUID.String())
+
+	contents, err := ioutil.ReadFile(existingKeyFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "previously-generated-key", string(contents))
+}
+
+func TestEncryptPartitionWithKeyfileSource(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "TestEncryptPartitionWithKeyfileSource")
+	if err != nil {
+		t.Fatalf("failed to create temp config dir: %+v", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	externalKeyFile := filepath.Join(configDir, "external.key")
+	assert.Nil(t, ioutil.WriteFile(externalKeyFile, []byte("a-pre-provisioned-key"), 0600))
+
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		switch args[0] {
+		case "isLuks":
+			return fmt.Errorf("not a luks device")
+		case "luksFormat", "open":
+			assert.Equal(t, externalKeyFile, requireKeyFileArg(t, args))
+		}
+		return nil
+	}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		return "33333333-3333-3333-3333-333333333333", nil
+	}
+
+	context := &clusterd.Context{EtcdClient: util.NewMockEtcdClient(), Executor: executor, ConfigDir: configDir}
+	p := &partition.PerfSchemePartitionDetails{
+		Type: "block", Device: "sda", Number: 3,
+		Encryption: &partition.EncryptionConfig{KeySource: partition.KeySourceKeyfile, KeyfilePath: externalKeyFile},
+	}
+
+	err = encryptPartitionIfNeeded(context, 1, p)
+	assert.Nil(t, err)
+}
+
+// requireKeyFileArg asserts that args contains "--key-file <path>" and returns the path.
+func requireKeyFileArg(t *testing.T, args []string) string {
+	for i, arg := range args {
+		if arg == "--key-file" {
+			assert.True(t, i+1 < len(args))
+			return args[i+1]
+		}
+	}
+	t.Fatalf("expected --key-file in cryptsetup args %+v", args)
+	return ""
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+// growBluestoreOSD grows an existing OSD's BLOCK partition in place when the
+// raw device backing it has grown, e.g. an expanded cloud volume. It is a
+// no-op when the device isn't rook-owned, and idempotent when there's no
+// growth to apply.
+func growBluestoreOSD(context *clusterd.Context, config *osdConfig) error {
+	device := config.partitionScheme.BlockPartition.Device
+	devicePath := fmt.Sprintf("/dev/%s", device)
+
+	partitions, disk, err := sys.GetDevicePartitions(device, context.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get partitions for %s: %+v", device, err)
+	}
+	if err := migrateLegacyLabelOwnership(context, partitions); err != nil {
+		return err
+	}
+	if !rookOwnsPartitions(context, partitions) {
+		logger.Infof("%s is not rook-owned; skipping grow", device)
+		return nil
+	}
+
+	blockLabel := fmt.Sprintf("ROOK-OSD%d-BLOCK", config.id)
+	var blockPart *sys.Partition
+	var usedBytes uint64
+	for _, p := range partitions {
+		usedBytes += p.Size
+		if p.PartLabel == blockLabel {
+			blockPart = p
+		}
+	}
+	if blockPart == nil {
+		return fmt.Errorf("could not find partition %s on %s", blockLabel, device)
+	}
+	if disk == nil || disk.Size <= usedBytes {
+		logger.Infof("%s has not grown; nothing to do", device)
+		return nil
+	}
+
+	if err := context.Executor.ExecuteCommand("partprobe", "partprobe", devicePath); err != nil {
+		return fmt.Errorf("failed to reread partition table on %s: %+v", device, err)
+	}
+	if err := context.Executor.ExecuteCommand("blockdev", "blockdev", "--rereadpt", devicePath); err != nil {
+		return fmt.Errorf("failed to reread partition table on %s: %+v", device, err)
+	}
+	if err := context.Executor.ExecuteCommand("sgdisk", "sgdisk", "--move-second-header", devicePath); err != nil {
+		return fmt.Errorf("failed to move backup gpt header on %s: %+v", device, err)
+	}
+	if err := context.Executor.ExecuteCommand("sgdisk", "sgdisk", "-e", devicePath); err != nil {
+		return fmt.Errorf("failed to relocate backup gpt header on %s: %+v", device, err)
+	}
+
+	blockNum := config.partitionScheme.BlockPartition.Number
+	if err := context.Executor.ExecuteCommand("sgdisk", "sgdisk", "-d", strconv.Itoa(blockNum), devicePath); err != nil {
+		return fmt.Errorf("failed to delete partition %d on %s: %+v", blockNum, device, err)
+	}
+
+	// preserve blockPart's own PARTUUID, as read from the device just above -
+	// not BlockPartition.DiskUUID, which for an encrypted partition has been
+	// overwritten with its LUKS2 UUID and is a different identifier entirely
+	if blockPart.PartUUID == "" {
+		return fmt.Errorf("partition %s on %s has no PARTUUID to preserve", blockLabel, device)
+	}
+	recreateArgs := []string{
+		fmt.Sprintf("--largest-new=%d", blockNum),
+		fmt.Sprintf("--change-name=%d:%s", blockNum, blockLabel),
+		fmt.Sprintf("--typecode=%d:%s", blockNum, partition.BluestoreTypeGUID),
+		fmt.Sprintf("--partition-guid=%d:%s", blockNum, blockPart.PartUUID),
+		devicePath,
+	}
+	if err := context.Executor.ExecuteCommand("sgdisk", "sgdisk", recreateArgs...); err != nil {
+		return fmt.Errorf("failed to recreate partition %d on %s: %+v", blockNum, device, err)
+	}
+
+	if err := context.Executor.ExecuteCommand("ceph-bluestore-tool", "ceph-bluestore-tool",
+		"bluefs-bdev-expand", "--path", config.rootPath); err != nil {
+		return fmt.Errorf("failed to expand bluefs bdev for osd %d: %+v", config.id, err)
+	}
+
+	return recordDeviceSize(context, config.partitionScheme.BlockPartition.DiskUUID, disk.Size)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGrowTestConfig(configDir string) *osdConfig {
+	entry := partition.NewPerfSchemeEntry()
+	entry.ID = 1
+	entry.OsdUUID = uuid.Must(uuid.NewRandom())
+	partition.PopulateCollocatedPerfSchemeEntry(entry, "sda", partition.BluestoreConfig{})
+	entry.BlockPartition.DiskUUID = uuid.Must(uuid.NewRandom())
+	return &osdConfig{configRoot: configDir, rootPath: filepath.Join(configDir, "osd1"), id: entry.ID,
+		uuid: entry.OsdUUID, partitionScheme: entry}
+}
+
+func TestGrowBluestoreOSD(t *testing.T) {
+	etcdClient := util.NewMockEtcdClient()
+	executor := &exectest.MockExecutor{}
+
+	sgdiskCalls := [][]string{}
+	bluestoreToolCalls := [][]string{}
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		switch command {
+		case "sgdisk":
+			sgdiskCalls = append(sgdiskCalls, args)
+		case "ceph-bluestore-tool":
+			bluestoreToolCalls = append(bluestoreToolCalls, args)
+		}
+		return nil
+	}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		assert.Equal(t, "lsblk", command)
+		return `NAME="sda" SIZE="200" TYPE="disk" PKNAME="" PARTLABEL=""
+NAME="sda1" SIZE="30" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-WAL" PARTUUID="aaaaaaaa-0000-0000-0000-000000000001"
+NAME="sda2" SIZE="10" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-DB" PARTUUID="aaaaaaaa-0000-0000-0000-000000000002"
+NAME="sda3" SIZE="20" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-BLOCK" PARTUUID="aaaaaaaa-0000-0000-0000-000000000003"`, nil
+	}
+
+	context := &clusterd.Context{EtcdClient: etcdClient, Executor: executor, NodeID: "node123"}
+	config := newGrowTestConfig(t.TempDir())
+
+	err := growBluestoreOSD(context, config)
+	assert.Nil(t, err)
+
+	// move-second-header, -e, -d <n>, recreate == 4 sgdisk invocations
+	assert.Equal(t, 4, len(sgdiskCalls))
+	assert.Equal(t, []string{"--move-second-header", "/dev/sda"}, sgdiskCalls[0])
+	assert.Equal(t, []string{"-e", "/dev/sda"}, sgdiskCalls[1])
+	assert.Equal(t, []string{"-d", "3", "/dev/sda"}, sgdiskCalls[2])
+
+	// the recreated BLOCK partition must keep its original PARTUUID, not the
+	// scheme's DiskUUID (which, for an encrypted partition, is the LUKS UUID)
+	assert.Equal(t, []string{
+		"--largest-new=3",
+		"--change-name=3:ROOK-OSD1-BLOCK",
+		fmt.Sprintf("--typecode=3:%s", partition.BluestoreTypeGUID),
+		"--partition-guid=3:aaaaaaaa-0000-0000-0000-000000000003",
+		"/dev/sda",
+	}, sgdiskCalls[3])
+
+	assert.Equal(t, 1, len(bluestoreToolCalls))
+	assert.Equal(t, []string{"bluefs-bdev-expand", "--path", config.rootPath}, bluestoreToolCalls[0])
+}
+
+func TestGrowBluestoreOSDIsIdempotentWhenNoGrowthOccurred(t *testing.T) {
+	etcdClient := util.NewMockEtcdClient()
+	executor := &exectest.MockExecutor{}
+
+	sgdiskCalls := 0
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		if command == "sgdisk" {
+			sgdiskCalls++
+		}
+		return nil
+	}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		return `NAME="sda" SIZE="60" TYPE="disk" PKNAME="" PARTLABEL=""
+NAME="sda1" SIZE="30" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-WAL"
+NAME="sda2" SIZE="10" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-DB"
+NAME="sda3" SIZE="20" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-BLOCK"`, nil
+	}
+
+	context := &clusterd.Context{EtcdClient: etcdClient, Executor: executor, NodeID: "node123"}
+	config := newGrowTestConfig(t.TempDir())
+
+	err := growBluestoreOSD(context, config)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, sgdiskCalls)
+}
+
+func TestGrowBluestoreOSDSkipsNonRookOwnedDisk(t *testing.T) {
+	etcdClient := util.NewMockEtcdClient()
+	executor := &exectest.MockExecutor{}
+
+	sgdiskCalls := 0
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		sgdiskCalls++
+		return nil
+	}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		return `NAME="sda" SIZE="200" TYPE="disk" PKNAME="" PARTLABEL=""
+NAME="sda1" SIZE="30" TYPE="part" PKNAME="sda" PARTLABEL="SOMEOTHEROWNER"`, nil
+	}
+
+	context := &clusterd.Context{EtcdClient: etcdClient, Executor: executor, NodeID: "node123"}
+	config := newGrowTestConfig(t.TempDir())
+
+	err := growBluestoreOSD(context, config)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, sgdiskCalls)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+// recordDeviceIdentity mirrors a per-node device record under a node-agnostic
+// index keyed only by the device's PARTUUID/LUKS UUID. PARTUUID and LUKS
+// UUIDs are unique cluster-wide, so unlike the per-node record, this index
+// still resolves correctly after the disk is moved to another node.
+func recordDeviceIdentity(context *clusterd.Context, diskUUID fmt.Stringer, key, value string) error {
+	return context.EtcdClient.SetValue(deviceIdentityKey(diskUUID, key), value)
+}
+
+func deviceIdentityKey(diskUUID fmt.Stringer, key string) string {
+	return fmt.Sprintf("/rook/services/ceph/osd/desired/device/%s/%s", diskUUID, key)
+}
+
+// legacyLabelOwnerKey is the pre-UUID etcd layout: ownership keyed directly
+// off the (node-scoped, collision-prone) PARTLABEL string.
+func legacyLabelOwnerKey(nodeID, partLabel, key string) string {
+	return fmt.Sprintf("/rook/services/ceph/osd/desired/%s/device/%s/%s", nodeID, partLabel, key)
+}
+
+// isRookOwnedByIdentity reports whether p's PARTUUID is present in the
+// node-agnostic device identity index under osd-id-data or osd-id-metadata.
+// Only the PARTUUID is looked up: an encrypted volume, whose records are keyed
+// by its LUKS2 UUID, is not found here. Format and grow gate on
+// rookOwnsPartitions, which calls this first and falls back to a PARTLABEL
+// check for partitions it doesn't find.
+func isRookOwnedByIdentity(context *clusterd.Context, p *sys.Partition) bool {
+	if p.PartUUID == "" {
+		return false
+	}
+	return context.EtcdClient.GetValue(deviceIdentityKey(stringerUUID(p.PartUUID), "osd-id-data")) != "" ||
+		context.EtcdClient.GetValue(deviceIdentityKey(stringerUUID(p.PartUUID), "osd-id-metadata")) != ""
+}
+
+type stringerUUID string
+
+func (s stringerUUID) String() string { return string(s) }
+
+// migrateLegacyLabelOwnership backfills the node-agnostic device identity
+// index from the legacy, label-keyed etcd entries the very first time it
+// notices a rook-owned partition it hasn't indexed by UUID yet. This lets an
+// upgraded node keep recognizing its existing OSDs without a cluster wipe.
+func migrateLegacyLabelOwnership(context *clusterd.Context, partitions []*sys.Partition) error {
+	for _, p := range partitions {
+		if p.PartUUID == "" || p.PartLabel == "" {
+			continue
+		}
+		if isRookOwnedByIdentity(context, p) {
+			continue // already migrated
+		}
+		for _, key := range []string{"osd-id-data", "osd-id-metadata"} {
+			legacyValue := context.EtcdClient.GetValue(legacyLabelOwnerKey(context.NodeID, p.PartLabel, key))
+			if legacyValue == "" {
+				continue
+			}
+			if err := recordDeviceIdentity(context, stringerUUID(p.PartUUID), key, legacyValue); err != nil {
+				return fmt.Errorf("failed to migrate legacy ownership for %s: %+v", p.PartLabel, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util"
+	"github.com/rook/rook/pkg/util/sys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateLegacyLabelOwnershipBackfillsUUIDIndex(t *testing.T) {
+	etcdClient := util.NewMockEtcdClient()
+	context := &clusterd.Context{EtcdClient: etcdClient, NodeID: "node123"}
+
+	// simulate a pre-upgrade cluster that only recorded ownership by label
+	etcdClient.SetValue(legacyLabelOwnerKey("node123", "ROOK-OSD1-BLOCK", "osd-id-data"), "1")
+
+	partitions := []*sys.Partition{
+		{Name: "sda1", PartLabel: "ROOK-OSD1-BLOCK", PartUUID: "aaaa-bbbb"},
+	}
+
+	err := migrateLegacyLabelOwnership(context, partitions)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", etcdClient.GetValue(deviceIdentityKey(stringerUUID("aaaa-bbbb"), "osd-id-data")))
+}
+
+func TestDiskRecognizedAfterMovingToAnotherNode(t *testing.T) {
+	etcdClient := util.NewMockEtcdClient()
+
+	// the OSD was originally provisioned on node1
+	originalContext := &clusterd.Context{EtcdClient: etcdClient, NodeID: "node1"}
+	assert.Nil(t, recordDeviceIdentity(originalContext, stringerUUID("same-disk-uuid"), "osd-id-data", "7"))
+
+	// the disk is now plugged into node2; the uuid index doesn't care which
+	// node asks, so the OSD is still recognized
+	p := &sys.Partition{Name: "sda1", PartLabel: "ROOK-OSD7-BLOCK", PartUUID: "same-disk-uuid"}
+	movedContext := &clusterd.Context{EtcdClient: etcdClient, NodeID: "node2"}
+	assert.True(t, isRookOwnedByIdentity(movedContext, p))
+}
+
+func TestRookOwnsPartitionsPrefersIdentityIndexOverLabel(t *testing.T) {
+	etcdClient := util.NewMockEtcdClient()
+	context := &clusterd.Context{EtcdClient: etcdClient, NodeID: "node123"}
+
+	// a partition genuinely provisioned by rook is indexed by its PARTUUID, so
+	// isRookOwnedByIdentity - the authoritative check - recognizes it directly
+	assert.Nil(t, recordDeviceIdentity(context, stringerUUID("real-rook-partuuid"), "osd-id-data", "1"))
+	owned := &sys.Partition{Name: "sda1", PartLabel: "ROOK-OSD1-BLOCK", PartUUID: "real-rook-partuuid"}
+	assert.True(t, isRookOwnedByIdentity(context, owned))
+	assert.True(t, rookOwnsPartitions(context, []*sys.Partition{owned}))
+
+	// a partition whose PARTUUID was never indexed isn't rook's by identity,
+	// even if it happens to carry rook's PARTLABEL convention - the label
+	// fallback in rookOwnsPartitions only exists for pre-migration disks and
+	// can't itself distinguish that case from this one
+	impostor := &sys.Partition{Name: "sdb1", PartLabel: "ROOK-OSD1-BLOCK", PartUUID: "unindexed-partuuid"}
+	assert.False(t, isRookOwnedByIdentity(context, impostor))
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package partition describes how an OSD's bluestore WAL, DB and BLOCK data
+// are laid out across one or more devices.
+package partition
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultWalSizeMB is used when a BluestoreConfig does not specify a WAL size.
+	DefaultWalSizeMB = 576
+	// DefaultDatabaseSizeMB is used when a BluestoreConfig does not specify a DB size.
+	DefaultDatabaseSizeMB = 1024
+)
+
+// KeySource identifies where the key used to unlock an encrypted partition
+// comes from.
+type KeySource string
+
+const (
+	// KeySourceRaw stores the raw key material directly.
+	KeySourceRaw KeySource = "raw"
+	// KeySourceKeyfile reads the key from a file on the host.
+	KeySourceKeyfile KeySource = "keyfile"
+	// KeySourceTang names a Tang server (clevis/tang binding) as the key
+	// source. No key is fetched from Tang: encrypting a partition with this
+	// source returns an error.
+	KeySourceTang KeySource = "tang"
+	// KeySourceKMIP names a KMIP-compatible key manager as the key source. No
+	// key is fetched over KMIP: encrypting a partition with this source
+	// returns an error.
+	KeySourceKMIP KeySource = "kmip"
+)
+
+// EncryptionConfig requests that an OSD's partitions be wrapped in a LUKS2
+// container before Ceph ever writes to them.
+type EncryptionConfig struct {
+	Cipher      string // e.g. "aes-xts-plain64", the cryptsetup --cipher value
+	KeySizeBits int    // e.g. 512, the cryptsetup --key-size value
+	KeySource   KeySource
+
+	// KeyfilePath is the path to an existing key file on the host to unlock
+	// with. Only meaningful when KeySource is KeySourceKeyfile.
+	KeyfilePath string
+}
+
+// GetCipher returns the configured cipher, or the cryptsetup default.
+func (e *EncryptionConfig) GetCipher() string {
+	if e.Cipher == "" {
+		return "aes-xts-plain64"
+	}
+	return e.Cipher
+}
+
+// GetKeySizeBits returns the configured key size, or the cryptsetup default.
+func (e *EncryptionConfig) GetKeySizeBits() int {
+	if e.KeySizeBits <= 0 {
+		return 512
+	}
+	return e.KeySizeBits
+}
+
+// BluestoreConfig controls the sizing of the WAL and DB partitions that back
+// a bluestore OSD. A zero value means "use the rook default".
+type BluestoreConfig struct {
+	WalSizeMB      int
+	DatabaseSizeMB int
+
+	// Encryption, when set, wraps the WAL, DB and BLOCK partitions in LUKS2
+	// containers so that Ceph only ever sees the decrypted /dev/mapper nodes.
+	// In a distributed layout only the BLOCK partition on the data disk is
+	// encrypted: the WAL and DB partitions on the shared metadata device are
+	// created without LUKS2.
+	Encryption *EncryptionConfig
+}
+
+// GetWalSizeMB returns the configured WAL size, or the rook default if unset.
+func (b BluestoreConfig) GetWalSizeMB() int {
+	if b.WalSizeMB <= 0 {
+		return DefaultWalSizeMB
+	}
+	return b.WalSizeMB
+}
+
+// GetDatabaseSizeMB returns the configured DB size, or the rook default if unset.
+func (b BluestoreConfig) GetDatabaseSizeMB() int {
+	if b.DatabaseSizeMB <= 0 {
+		return DefaultDatabaseSizeMB
+	}
+	return b.DatabaseSizeMB
+}
+
+// PerfSchemePartitionDetails describes a single partition that backs part of
+// an OSD: its WAL, its DB, or its data (BLOCK).
+type PerfSchemePartitionDetails struct {
+	Type     string // "wal", "db", or "block"
+	Device   string
+	Number   int
+	SizeMB   int
+	DiskUUID uuid.UUID
+
+	// Encryption, when set, means this partition is opened through
+	// cryptsetup before Ceph uses it; MapperName and LuksUUID are only
+	// meaningful once the partition has actually been formatted.
+	Encryption *EncryptionConfig
+	MapperName string
+	LuksUUID   uuid.UUID
+}
+
+// PartitionDevice returns the raw, unencrypted device node for this partition,
+// e.g. "/dev/sda1".
+func (p *PerfSchemePartitionDetails) PartitionDevice() string {
+	return fmt.Sprintf("/dev/%s%d", p.Device, p.Number)
+}
+
+// CephDevice returns the device node Ceph should be pointed at: the decrypted
+// /dev/mapper node when the partition is encrypted, or the raw partition
+// device node otherwise.
+func (p *PerfSchemePartitionDetails) CephDevice() string {
+	if p.Encryption != nil {
+		return "/dev/mapper/" + p.MapperName
+	}
+	return p.PartitionDevice()
+}
+
+// PerfSchemeEntry is the resolved partition layout for a single OSD.
+type PerfSchemeEntry struct {
+	ID                int
+	OsdUUID           uuid.UUID
+	StoreType         string
+	WalPartition      *PerfSchemePartitionDetails
+	DatabasePartition *PerfSchemePartitionDetails
+	BlockPartition    *PerfSchemePartitionDetails
+}
+
+// NewPerfSchemeEntry creates an empty bluestore partition scheme entry.
+func NewPerfSchemeEntry() *PerfSchemeEntry {
+	return &PerfSchemeEntry{StoreType: "bluestore"}
+}
+
+// PopulateCollocatedPerfSchemeEntry lays the WAL, DB and BLOCK partitions for
+// an OSD out on a single disk, in that order.
+func PopulateCollocatedPerfSchemeEntry(entry *PerfSchemeEntry, disk string, cfg BluestoreConfig) {
+	entry.WalPartition = &PerfSchemePartitionDetails{Type: "wal", Device: disk, Number: 1, SizeMB: cfg.GetWalSizeMB(), Encryption: cfg.Encryption}
+	entry.DatabasePartition = &PerfSchemePartitionDetails{Type: "db", Device: disk, Number: 2, SizeMB: cfg.GetDatabaseSizeMB(), Encryption: cfg.Encryption}
+	entry.BlockPartition = &PerfSchemePartitionDetails{Type: "block", Device: disk, Number: 3, Encryption: cfg.Encryption}
+}
+
+// MetadataPartition is a single WAL or DB partition that an OSD stores on a
+// shared metadata device.
+type MetadataPartition struct {
+	OsdID   int
+	OsdUUID uuid.UUID
+	Type    string // "wal" or "db"
+	Number  int
+	SizeMB  int
+}
+
+// MetadataDeviceInfo tracks the WAL/DB partitions that one or more OSDs have
+// placed on a shared metadata device, e.g. a fast NVMe disk.
+type MetadataDeviceInfo struct {
+	Device     string
+	DiskUUID   uuid.UUID
+	Partitions []*MetadataPartition
+}
+
+// NewMetadataDeviceInfo creates the metadata device tracker for the given disk.
+func NewMetadataDeviceInfo(device string) *MetadataDeviceInfo {
+	return &MetadataDeviceInfo{Device: device, DiskUUID: uuid.Must(uuid.NewRandom())}
+}
+
+// OsdIDs returns the distinct OSD ids that have metadata on this device.
+func (m *MetadataDeviceInfo) OsdIDs() []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, p := range m.Partitions {
+		if !seen[p.OsdID] {
+			seen[p.OsdID] = true
+			ids = append(ids, p.OsdID)
+		}
+	}
+	return ids
+}
+
+// PopulateDistributedPerfSchemeEntry lays an OSD's data out on its own disk
+// while its WAL and DB partitions are appended to the shared metadata device.
+func PopulateDistributedPerfSchemeEntry(entry *PerfSchemeEntry, dataDisk string, metadata *MetadataDeviceInfo, cfg BluestoreConfig) {
+	walNum := len(metadata.Partitions) + 1
+	metadata.Partitions = append(metadata.Partitions, &MetadataPartition{
+		OsdID: entry.ID, OsdUUID: entry.OsdUUID, Type: "wal", Number: walNum, SizeMB: cfg.GetWalSizeMB()})
+	entry.WalPartition = &PerfSchemePartitionDetails{Type: "wal", Device: metadata.Device, Number: walNum, SizeMB: cfg.GetWalSizeMB(), DiskUUID: metadata.DiskUUID, Encryption: cfg.Encryption}
+
+	dbNum := len(metadata.Partitions) + 1
+	metadata.Partitions = append(metadata.Partitions, &MetadataPartition{
+		OsdID: entry.ID, OsdUUID: entry.OsdUUID, Type: "db", Number: dbNum, SizeMB: cfg.GetDatabaseSizeMB()})
+	entry.DatabasePartition = &PerfSchemePartitionDetails{Type: "db", Device: metadata.Device, Number: dbNum, SizeMB: cfg.GetDatabaseSizeMB(), DiskUUID: metadata.DiskUUID, Encryption: cfg.Encryption}
+
+	entry.BlockPartition = &PerfSchemePartitionDetails{Type: "block", Device: dataDisk, Number: 1, Encryption: cfg.Encryption}
+}
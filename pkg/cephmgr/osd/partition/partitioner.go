@@ -0,0 +1,187 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partition
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/util/exec"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "cephmgr-osd-partition")
+
+// BluestoreTypeGUID is the GPT partition type GUID ceph-disk uses for
+// bluestore block/WAL/DB partitions.
+const BluestoreTypeGUID = "cafecafe-9b03-4f30-b4c6-b4b80ceff106"
+
+// PartitionSpec describes a single partition to create on a device, in terms
+// a partitioner backend turns into whatever tool invocation it needs. A
+// SizeMB of 0 means "consume the rest of the disk" rather than a fixed size.
+type PartitionSpec struct {
+	Number   int
+	Label    string
+	SizeMB   int
+	TypeGUID string
+	// UUID is only populated by ReadLayout, once the partition actually exists.
+	UUID string
+}
+
+// DiskPartitioner lays out partitions on a raw block device. It exists so
+// that sgdisk isn't the only way to do it: a dry-run mode, a parted-based
+// backend for MBR-only environments, or a pure-Go GPT writer can all be
+// added as implementations without touching its callers.
+type DiskPartitioner interface {
+	// Zap destroys any existing partition table on device.
+	Zap(device string) error
+	// CreatePartitions lays out specs on device, in order, overwriting
+	// whatever was zapped. The device must already be zapped.
+	CreatePartitions(device string, specs []PartitionSpec) error
+	// ReadLayout returns the partitions currently on device.
+	ReadLayout(device string) ([]PartitionSpec, error)
+}
+
+// SgdiskPartitioner is the default DiskPartitioner: it shells out to sgdisk,
+// preserving the exact argument order rook has always used.
+type SgdiskPartitioner struct {
+	Executor exec.Executor
+}
+
+func (s *SgdiskPartitioner) Zap(device string) error {
+	devicePath := fmt.Sprintf("/dev/%s", device)
+	if err := s.Executor.ExecuteCommand("sgdisk", "sgdisk", "--zap-all", devicePath); err != nil {
+		return fmt.Errorf("failed to zap %s: %+v", device, err)
+	}
+	if err := s.Executor.ExecuteCommand("sgdisk", "sgdisk", "--clear", "--mbrtogpt", devicePath); err != nil {
+		return fmt.Errorf("failed to clear %s: %+v", device, err)
+	}
+	return nil
+}
+
+func (s *SgdiskPartitioner) CreatePartitions(device string, specs []PartitionSpec) error {
+	args := []string{}
+	for _, spec := range specs {
+		if spec.SizeMB > 0 {
+			args = append(args, fmt.Sprintf("--new=%d:0:+%dM", spec.Number, spec.SizeMB))
+		} else {
+			args = append(args, fmt.Sprintf("--largest-new=%d", spec.Number))
+		}
+		args = append(args, fmt.Sprintf("--change-name=%d:%s", spec.Number, spec.Label))
+		args = append(args, fmt.Sprintf("--typecode=%d:%s", spec.Number, spec.TypeGUID))
+	}
+	args = append(args, fmt.Sprintf("/dev/%s", device))
+
+	if err := s.Executor.ExecuteCommand("sgdisk", "sgdisk", args...); err != nil {
+		return fmt.Errorf("failed to partition %s: %+v", device, err)
+	}
+	return nil
+}
+
+func (s *SgdiskPartitioner) ReadLayout(device string) ([]PartitionSpec, error) {
+	partitions, _, err := sys.GetDevicePartitions(device, s.Executor)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make([]PartitionSpec, 0, len(partitions))
+	for _, p := range partitions {
+		specs = append(specs, PartitionSpec{
+			Number: partitionNumber(p.Name),
+			Label:  p.PartLabel,
+			UUID:   p.PartUUID,
+		})
+	}
+	return specs, nil
+}
+
+// partitionNumber extracts the trailing partition number from a device name
+// like "sda3", returning 0 if there isn't one.
+func partitionNumber(name string) int {
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+	digits := name[i:]
+	if digits == "" {
+		return 0
+	}
+	n := 0
+	for _, d := range digits {
+		n = n*10 + int(d-'0')
+	}
+	return n
+}
+
+// DryRunPartitioner logs what it would do instead of touching the disk. Reads
+// are safe, so ReadLayout is delegated to a real partitioner when one is set.
+type DryRunPartitioner struct {
+	Delegate DiskPartitioner
+}
+
+func (d *DryRunPartitioner) Zap(device string) error {
+	logger.Infof("dry-run: would zap %s", device)
+	return nil
+}
+
+func (d *DryRunPartitioner) CreatePartitions(device string, specs []PartitionSpec) error {
+	labels := make([]string, len(specs))
+	for i, spec := range specs {
+		labels[i] = spec.Label
+	}
+	logger.Infof("dry-run: would create partitions %s on %s", strings.Join(labels, ", "), device)
+	return nil
+}
+
+func (d *DryRunPartitioner) ReadLayout(device string) ([]PartitionSpec, error) {
+	if d.Delegate == nil {
+		return nil, nil
+	}
+	return d.Delegate.ReadLayout(device)
+}
+
+// ParsedPartitioner is a DiskPartitioner test double that records the calls
+// made to it as structured PartitionSpecs, instead of requiring tests to
+// pattern-match sgdisk argv strings.
+type ParsedPartitioner struct {
+	ZappedDevices  []string
+	CreatedSpecs   map[string][]PartitionSpec
+	StubbedLayouts map[string][]PartitionSpec
+}
+
+// NewParsedPartitioner creates an empty ParsedPartitioner ready for use in tests.
+func NewParsedPartitioner() *ParsedPartitioner {
+	return &ParsedPartitioner{
+		CreatedSpecs:   map[string][]PartitionSpec{},
+		StubbedLayouts: map[string][]PartitionSpec{},
+	}
+}
+
+func (p *ParsedPartitioner) Zap(device string) error {
+	p.ZappedDevices = append(p.ZappedDevices, device)
+	return nil
+}
+
+func (p *ParsedPartitioner) CreatePartitions(device string, specs []PartitionSpec) error {
+	p.CreatedSpecs[device] = append(p.CreatedSpecs[device], specs...)
+	return nil
+}
+
+func (p *ParsedPartitioner) ReadLayout(device string) ([]PartitionSpec, error) {
+	return p.StubbedLayouts[device], nil
+}
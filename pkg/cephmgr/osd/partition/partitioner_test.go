@@ -0,0 +1,95 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partition
+
+import (
+	"fmt"
+	"testing"
+
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSgdiskPartitionerZap(t *testing.T) {
+	var calls [][]string
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		assert.Equal(t, "sgdisk", command)
+		calls = append(calls, args)
+		return nil
+	}
+
+	s := &SgdiskPartitioner{Executor: executor}
+	err := s.Zap("sda")
+	assert.Nil(t, err)
+
+	assert.Equal(t, [][]string{
+		{"--zap-all", "/dev/sda"},
+		{"--clear", "--mbrtogpt", "/dev/sda"},
+	}, calls)
+}
+
+func TestSgdiskPartitionerCreatePartitions(t *testing.T) {
+	var calls [][]string
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommand = func(name string, command string, args ...string) error {
+		assert.Equal(t, "sgdisk", command)
+		calls = append(calls, args)
+		return nil
+	}
+
+	s := &SgdiskPartitioner{Executor: executor}
+	err := s.CreatePartitions("sda", []PartitionSpec{
+		{Number: 1, Label: "ROOK-OSD1-WAL", SizeMB: 576, TypeGUID: BluestoreTypeGUID},
+		{Number: 2, Label: "ROOK-OSD1-DB", SizeMB: 1024, TypeGUID: BluestoreTypeGUID},
+		{Number: 3, Label: "ROOK-OSD1-BLOCK", TypeGUID: BluestoreTypeGUID}, // SizeMB 0: consume the rest of the disk
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(calls))
+	assert.Equal(t, []string{
+		"--new=1:0:+576M",
+		"--change-name=1:ROOK-OSD1-WAL",
+		fmt.Sprintf("--typecode=1:%s", BluestoreTypeGUID),
+		"--new=2:0:+1024M",
+		"--change-name=2:ROOK-OSD1-DB",
+		fmt.Sprintf("--typecode=2:%s", BluestoreTypeGUID),
+		"--largest-new=3",
+		"--change-name=3:ROOK-OSD1-BLOCK",
+		fmt.Sprintf("--typecode=3:%s", BluestoreTypeGUID),
+		"/dev/sda",
+	}, calls[0])
+}
+
+func TestSgdiskPartitionerReadLayout(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		assert.Equal(t, "lsblk", command)
+		return `NAME="sda" SIZE="200" TYPE="disk" PKNAME="" PARTLABEL=""
+NAME="sda1" SIZE="30" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-WAL" PARTUUID="aaaa-0000"
+NAME="sda2" SIZE="10" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-DB" PARTUUID="aaaa-0001"`, nil
+	}
+
+	s := &SgdiskPartitioner{Executor: executor}
+	layout, err := s.ReadLayout("sda")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []PartitionSpec{
+		{Number: 1, Label: "ROOK-OSD1-WAL", UUID: "aaaa-0000"},
+		{Number: 2, Label: "ROOK-OSD1-DB", UUID: "aaaa-0001"},
+	}, layout)
+}
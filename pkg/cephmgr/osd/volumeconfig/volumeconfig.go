@@ -0,0 +1,160 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package volumeconfig lets an operator declare, per node or per disk
+// selector, how an OSD's bluestore partitions should be laid out, instead of
+// that choice being hard-coded into the controller's Go code. The shape is
+// modeled after Talos's block/volume configuration: a list of rules, each
+// with a selector and a resulting layout, evaluated first-match-wins.
+package volumeconfig
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/clusterd/inventory"
+)
+
+// Layout is the shape of an OSD's bluestore partitions.
+type Layout string
+
+const (
+	// LayoutCollocated puts an OSD's WAL, DB and BLOCK partitions on the same disk.
+	LayoutCollocated Layout = "collocated"
+	// LayoutDistributed puts an OSD's WAL and DB on a separate, named metadata device.
+	LayoutDistributed Layout = "distributed"
+)
+
+// DiskSelector matches a subset of the disks discovered on a node. A zero
+// value field is not evaluated, so an empty DiskSelector matches every disk.
+type DiskSelector struct {
+	// MinSizeBytes requires the disk to be at least this large.
+	MinSizeBytes uint64
+	// Model requires an exact match on the disk's reported model string.
+	Model string
+	// WWN requires an exact match on the disk's World Wide Name.
+	WWN string
+	// ByPath requires an exact match on the disk's stable /dev/disk/by-path name.
+	ByPath string
+	// Rotational, when set, requires the disk to be (or not be) a spinning disk.
+	Rotational *bool
+}
+
+// Matches returns true if disk satisfies every field set on the selector.
+func (s DiskSelector) Matches(disk *inventory.LocalDisk) bool {
+	if s.MinSizeBytes > 0 && disk.Size < s.MinSizeBytes {
+		return false
+	}
+	if s.Model != "" && disk.Model != s.Model {
+		return false
+	}
+	if s.WWN != "" && disk.WWN != s.WWN {
+		return false
+	}
+	if s.ByPath != "" && disk.ByPathName != s.ByPath {
+		return false
+	}
+	if s.Rotational != nil && disk.Rotational != *s.Rotational {
+		return false
+	}
+	return true
+}
+
+// VolumeConfig is a single rule: on nodes matching NodeSelector, for disks
+// matching DiskSelector, lay the OSD out as Layout describes.
+type VolumeConfig struct {
+	// Name identifies the rule in error messages and logs.
+	Name string
+	// NodeSelector is a node id, or "" / "*" to match every node.
+	NodeSelector string
+	DiskSelector DiskSelector
+	Layout       Layout
+	// MetadataDevice names the disk that WAL/DB partitions land on when
+	// Layout is LayoutDistributed. Ignored for LayoutCollocated.
+	MetadataDevice string
+	Bluestore      partition.BluestoreConfig
+
+	// AlignmentMB rounds the BLOCK partition's size down to a multiple of
+	// this many megabytes. Zero leaves sizing unrounded.
+	AlignmentMB int
+	// MinSizeMB is the smallest total size, summed across the OSD partitions
+	// this rule places on the disk itself (WAL, DB and BLOCK when collocated,
+	// BLOCK alone when distributed), that this rule will accept for a disk. Resolving
+	// the rule against a disk that can't meet it fails rather than silently
+	// producing an undersized OSD. Zero means no minimum is enforced.
+	MinSizeMB int
+	// ReservedTrailingMB is left unpartitioned at the end of the disk, e.g.
+	// to stay clear of a vendor's overprovisioning area.
+	ReservedTrailingMB int
+}
+
+// ApplyDefaults fills in any fields the operator left unset.
+func (v *VolumeConfig) ApplyDefaults() {
+	if v.Layout == "" {
+		v.Layout = LayoutCollocated
+	}
+}
+
+// Validate returns an error if the rule is self-contradictory.
+func (v *VolumeConfig) Validate() error {
+	if v.AlignmentMB < 0 {
+		return fmt.Errorf("volume config %q: alignmentMB must not be negative", v.Name)
+	}
+	if v.MinSizeMB < 0 {
+		return fmt.Errorf("volume config %q: minSizeMB must not be negative", v.Name)
+	}
+	if v.ReservedTrailingMB < 0 {
+		return fmt.Errorf("volume config %q: reservedTrailingMB must not be negative", v.Name)
+	}
+	switch v.Layout {
+	case LayoutCollocated:
+		// no additional fields required
+	case LayoutDistributed:
+		if v.MetadataDevice == "" {
+			return fmt.Errorf("volume config %q: distributed layout requires metadataDevice", v.Name)
+		}
+	default:
+		return fmt.Errorf("volume config %q: unknown layout %q", v.Name, v.Layout)
+	}
+	return nil
+}
+
+// Set is an ordered list of volume config rules, evaluated first-match-wins.
+type Set []*VolumeConfig
+
+// Validate checks every rule in the set.
+func (s Set) Validate() error {
+	for _, v := range s {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match returns the first rule in the set whose NodeSelector and DiskSelector
+// both match, or nil if none apply.
+func (s Set) Match(nodeID string, disk *inventory.LocalDisk) *VolumeConfig {
+	for _, v := range s {
+		if v.NodeSelector != "" && v.NodeSelector != "*" && v.NodeSelector != nodeID {
+			continue
+		}
+		if v.DiskSelector.Matches(disk) {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volumeconfig
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd/inventory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskSelectorMatches(t *testing.T) {
+	rotational := true
+	selector := DiskSelector{MinSizeBytes: 4 * 1024 * 1024 * 1024 * 1024, Rotational: &rotational}
+
+	small := &inventory.LocalDisk{Name: "sda", Size: 1024, Rotational: true}
+	assert.False(t, selector.Matches(small))
+
+	ssd := &inventory.LocalDisk{Name: "sdb", Size: 8 * 1024 * 1024 * 1024 * 1024, Rotational: false}
+	assert.False(t, selector.Matches(ssd))
+
+	bigSpinner := &inventory.LocalDisk{Name: "sdc", Size: 8 * 1024 * 1024 * 1024 * 1024, Rotational: true}
+	assert.True(t, selector.Matches(bigSpinner))
+}
+
+func TestSetMatchFirstMatchWins(t *testing.T) {
+	set := Set{
+		{Name: "nvme-metadata", DiskSelector: DiskSelector{Model: "nvme0n1"}, Layout: LayoutCollocated},
+		{Name: "fallback", DiskSelector: DiskSelector{}, Layout: LayoutDistributed, MetadataDevice: "nvme0n1"},
+	}
+
+	nvme := &inventory.LocalDisk{Name: "nvme0n1", Model: "nvme0n1"}
+	match := set.Match("node1", nvme)
+	assert.NotNil(t, match)
+	assert.Equal(t, "nvme-metadata", match.Name)
+
+	hdd := &inventory.LocalDisk{Name: "sda", Model: "hdd"}
+	match = set.Match("node1", hdd)
+	assert.NotNil(t, match)
+	assert.Equal(t, "fallback", match.Name)
+}
+
+func TestSetMatchNodeSelector(t *testing.T) {
+	set := Set{{Name: "node2-only", NodeSelector: "node2", Layout: LayoutCollocated}}
+	disk := &inventory.LocalDisk{Name: "sda"}
+
+	assert.Nil(t, set.Match("node1", disk))
+	assert.NotNil(t, set.Match("node2", disk))
+}
+
+func TestValidateRequiresMetadataDeviceForDistributed(t *testing.T) {
+	v := &VolumeConfig{Name: "bad", Layout: LayoutDistributed}
+	assert.NotNil(t, v.Validate())
+
+	v.MetadataDevice = "nvme0n1"
+	assert.Nil(t, v.Validate())
+}
+
+func TestApplyDefaults(t *testing.T) {
+	v := &VolumeConfig{Name: "defaulted"}
+	v.ApplyDefaults()
+	assert.Equal(t, LayoutCollocated, v.Layout)
+}
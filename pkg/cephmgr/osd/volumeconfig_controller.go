@@ -0,0 +1,107 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/cephmgr/osd/volumeconfig"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/clusterd/inventory"
+)
+
+// resolvePerfScheme finds the volume config rule that applies to disk (if
+// any) and turns it into a PerfSchemeEntry, allocating the disk's shared
+// metadata device from metadataDevices if the rule calls for a distributed
+// layout. It returns nil, nil when no rule matches the disk, leaving the
+// caller to fall back to its own default layout.
+func resolvePerfScheme(context *clusterd.Context, configs volumeconfig.Set, disk *inventory.LocalDisk,
+	metadataDevices map[string]*partition.MetadataDeviceInfo, osdID int) (*partition.PerfSchemeEntry, error) {
+
+	rule := configs.Match(context.NodeID, disk)
+	if rule == nil {
+		return nil, nil
+	}
+	rule.ApplyDefaults()
+	if err := rule.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid volume config %q for disk %s: %+v", rule.Name, disk.Name, err)
+	}
+
+	entry := partition.NewPerfSchemeEntry()
+	entry.ID = osdID
+
+	switch rule.Layout {
+	case volumeconfig.LayoutCollocated:
+		partition.PopulateCollocatedPerfSchemeEntry(entry, disk.Name, rule.Bluestore)
+	case volumeconfig.LayoutDistributed:
+		metadata, ok := metadataDevices[rule.MetadataDevice]
+		if !ok {
+			metadata = partition.NewMetadataDeviceInfo(rule.MetadataDevice)
+			metadataDevices[rule.MetadataDevice] = metadata
+		}
+		partition.PopulateDistributedPerfSchemeEntry(entry, disk.Name, metadata, rule.Bluestore)
+	}
+
+	if err := applyVolumeConfigSizing(entry, disk, rule); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+const megabyte = uint64(1024 * 1024)
+
+// applyVolumeConfigSizing sizes entry's BLOCK partition from rule's
+// alignment/min-size/reserved-trailing-space constraints. A rule that leaves
+// all three at their zero value opts out of this entirely, and the BLOCK
+// partition keeps consuming the rest of the disk exactly as it did before
+// these fields existed.
+func applyVolumeConfigSizing(entry *partition.PerfSchemeEntry, disk *inventory.LocalDisk, rule *volumeconfig.VolumeConfig) error {
+	if rule.AlignmentMB == 0 && rule.MinSizeMB == 0 && rule.ReservedTrailingMB == 0 {
+		return nil
+	}
+
+	diskMB := disk.Size / megabyte
+	var usedMB uint64
+	for _, p := range []*partition.PerfSchemePartitionDetails{entry.WalPartition, entry.DatabasePartition} {
+		if p != nil && p.Device == entry.BlockPartition.Device {
+			usedMB += uint64(p.SizeMB)
+		}
+	}
+
+	reservedMB := uint64(rule.ReservedTrailingMB)
+	if diskMB <= usedMB+reservedMB {
+		return fmt.Errorf("volume config %q: disk %s (%dMB) is too small for its WAL/DB partitions plus the %dMB reserved trailing space",
+			rule.Name, disk.Name, diskMB, rule.ReservedTrailingMB)
+	}
+	blockMB := diskMB - usedMB - reservedMB
+	if rule.AlignmentMB > 0 {
+		blockMB -= blockMB % uint64(rule.AlignmentMB)
+	}
+	entry.BlockPartition.SizeMB = int(blockMB)
+
+	if rule.MinSizeMB > 0 {
+		totalMB := int(usedMB) + entry.BlockPartition.SizeMB
+		if totalMB < rule.MinSizeMB {
+			return fmt.Errorf("volume config %q: resolved layout for disk %s totals %dMB, below minSizeMB %d",
+				rule.Name, disk.Name, totalMB, rule.MinSizeMB)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/cephmgr/osd/volumeconfig"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/clusterd/inventory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePerfSchemeCollocated(t *testing.T) {
+	context := &clusterd.Context{NodeID: "node1"}
+	configs := volumeconfig.Set{
+		{Name: "big-disks", DiskSelector: volumeconfig.DiskSelector{MinSizeBytes: 100}, Layout: volumeconfig.LayoutCollocated},
+	}
+	disk := &inventory.LocalDisk{Name: "sda", Size: 200}
+
+	entry, err := resolvePerfScheme(context, configs, disk, map[string]*partition.MetadataDeviceInfo{}, 1)
+	assert.Nil(t, err)
+	assert.NotNil(t, entry)
+	assert.Equal(t, "sda", entry.BlockPartition.Device)
+	assert.Equal(t, "sda", entry.WalPartition.Device)
+}
+
+func TestResolvePerfSchemeDistributedSharesMetadataDevice(t *testing.T) {
+	context := &clusterd.Context{NodeID: "node1"}
+	configs := volumeconfig.Set{
+		{Name: "spinners", DiskSelector: volumeconfig.DiskSelector{}, Layout: volumeconfig.LayoutDistributed, MetadataDevice: "nvme0n1"},
+	}
+	metadataDevices := map[string]*partition.MetadataDeviceInfo{}
+
+	sdb, err := resolvePerfScheme(context, configs, &inventory.LocalDisk{Name: "sdb"}, metadataDevices, 1)
+	assert.Nil(t, err)
+	sdc, err := resolvePerfScheme(context, configs, &inventory.LocalDisk{Name: "sdc"}, metadataDevices, 2)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "nvme0n1", sdb.WalPartition.Device)
+	assert.Equal(t, "nvme0n1", sdc.WalPartition.Device)
+	assert.Len(t, metadataDevices, 1)
+	assert.Len(t, metadataDevices["nvme0n1"].Partitions, 4) // wal+db for both osds
+}
+
+func TestResolvePerfSchemeNoMatch(t *testing.T) {
+	context := &clusterd.Context{NodeID: "node1"}
+	entry, err := resolvePerfScheme(context, volumeconfig.Set{}, &inventory.LocalDisk{Name: "sda"}, map[string]*partition.MetadataDeviceInfo{}, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestResolvePerfSchemeAppliesAlignmentAndReservedTrailingSpace(t *testing.T) {
+	context := &clusterd.Context{NodeID: "node1"}
+	configs := volumeconfig.Set{
+		{Name: "aligned", Layout: volumeconfig.LayoutCollocated, AlignmentMB: 1000, MinSizeMB: 5000, ReservedTrailingMB: 100},
+	}
+	disk := &inventory.LocalDisk{Name: "sda", Size: 10000 * megabyte}
+
+	entry, err := resolvePerfScheme(context, configs, disk, map[string]*partition.MetadataDeviceInfo{}, 1)
+	assert.Nil(t, err)
+
+	// default wal (576MB) + db (1024MB) = 1600MB used; 10000 - 1600 - 100
+	// reserved = 8300MB, rounded down to the nearest 1000MB alignment = 8000MB
+	assert.Equal(t, 8000, entry.BlockPartition.SizeMB)
+}
+
+func TestResolvePerfSchemeRejectsDiskBelowMinSize(t *testing.T) {
+	context := &clusterd.Context{NodeID: "node1"}
+	configs := volumeconfig.Set{
+		{Name: "aligned", Layout: volumeconfig.LayoutCollocated, MinSizeMB: 50000},
+	}
+	disk := &inventory.LocalDisk{Name: "sda", Size: 10000 * megabyte}
+
+	entry, err := resolvePerfScheme(context, configs, disk, map[string]*partition.MetadataDeviceInfo{}, 1)
+	assert.NotNil(t, err)
+	assert.Nil(t, entry)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/cephmgr/osd/volumeconfig"
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+// ProvisionVolumeConfigOSDs matches configs against every disk this node's
+// inventory reports, and formats/partitions the ones a rule matches. A disk
+// no rule matches is left untouched, so an operator opts a disk in simply by
+// writing a selector broad enough to catch it. nextOsdID is called once per
+// matched disk, after the match is confirmed, to allocate that OSD's id;
+// this package has no id allocator of its own, so the caller is expected to
+// back it with the cluster's real one (e.g. a mon "osd create" call), and an
+// unmatched disk must never cost it an id.
+func ProvisionVolumeConfigOSDs(context *clusterd.Context, configs volumeconfig.Set, configDir string, nextOsdID func() int) error {
+	if err := configs.Validate(); err != nil {
+		return fmt.Errorf("invalid volume config: %+v", err)
+	}
+	if context.Inventory == nil || context.Inventory.Local == nil {
+		return nil
+	}
+
+	metadataDevices := map[string]*partition.MetadataDeviceInfo{}
+
+	for _, disk := range context.Inventory.Local.Disks {
+		rule := configs.Match(context.NodeID, disk)
+		if rule == nil {
+			continue // no volume config rule matches this disk
+		}
+		rule.ApplyDefaults()
+
+		osdID := nextOsdID()
+		entry, err := resolvePerfScheme(context, configs, disk, metadataDevices, osdID)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue // configs.Match and resolvePerfScheme agree on the same rule set; this can't happen
+		}
+
+		config := &osdConfig{configRoot: configDir, id: entry.ID, uuid: entry.OsdUUID, partitionScheme: entry}
+
+		if rule.Layout == volumeconfig.LayoutDistributed {
+			// the data disk only gets its BLOCK partition here; the WAL/DB
+			// partitions entry.* points at live on the shared metadata device,
+			// partitioned once below after every disk has registered its share
+			if err := formatDistributedDataDevice(context, config, false); err != nil {
+				return fmt.Errorf("failed to format data disk %s for osd %d: %+v", disk.Name, osdID, err)
+			}
+			continue
+		}
+
+		if err := formatDevice(context, config, false); err != nil {
+			return fmt.Errorf("failed to format disk %s for osd %d: %+v", disk.Name, osdID, err)
+		}
+	}
+
+	for _, metadata := range metadataDevices {
+		if err := partitionBluestoreMetadata(context, metadata, configDir); err != nil {
+			return fmt.Errorf("failed to partition metadata device %s: %+v", metadata.Device, err)
+		}
+	}
+
+	return nil
+}
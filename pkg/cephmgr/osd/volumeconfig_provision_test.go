@@ -0,0 +1,145 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package osd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/cephmgr/osd/volumeconfig"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/clusterd/inventory"
+	"github.com/rook/rook/pkg/util"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisionVolumeConfigOSDsFormatsMatchedDisks(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "TestProvisionVolumeConfigOSDsFormatsMatchedDisks")
+	assert.Nil(t, err)
+	defer os.RemoveAll(configDir)
+
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		return "", nil // fresh disk: no partitions from lsblk, no filesystem from df
+	}
+
+	context := &clusterd.Context{
+		EtcdClient:  util.NewMockEtcdClient(),
+		Executor:    executor,
+		NodeID:      "node123",
+		ConfigDir:   configDir,
+		Inventory:   createInventory(),
+		Partitioner: partition.NewParsedPartitioner(),
+	}
+	context.Inventory.Local.Disks = []*inventory.LocalDisk{
+		{Name: "sda", Size: 200 * megabyte, Rotational: true},
+		{Name: "ssd0", Size: 50 * megabyte, Rotational: false}, // not selected; rule only wants spinning disks
+	}
+
+	configs := volumeconfig.Set{
+		{Name: "spinners", DiskSelector: volumeconfig.DiskSelector{Rotational: boolPtr(true)},
+			Layout: volumeconfig.LayoutCollocated},
+	}
+
+	nextID := 0
+	allocated := 0
+	err = ProvisionVolumeConfigOSDs(context, configs, configDir, func() int {
+		nextID++
+		allocated++
+		return nextID
+	})
+	assert.Nil(t, err)
+
+	// ssd0 matched no rule, so it must never have cost an osd id
+	assert.Equal(t, 1, allocated)
+
+	parsedPartitioner := context.Partitioner.(*partition.ParsedPartitioner)
+	assert.Equal(t, []string{"sda"}, parsedPartitioner.ZappedDevices)
+	assert.Equal(t, 3, len(parsedPartitioner.CreatedSpecs["sda"]))
+}
+
+func TestProvisionVolumeConfigOSDsDistributedLayout(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "TestProvisionVolumeConfigOSDsDistributedLayout")
+	assert.Nil(t, err)
+	defer os.RemoveAll(configDir)
+
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		return "", nil // fresh disks: no partitions from lsblk, no filesystem from df
+	}
+
+	context := &clusterd.Context{
+		EtcdClient:  util.NewMockEtcdClient(),
+		Executor:    executor,
+		NodeID:      "node123",
+		ConfigDir:   configDir,
+		Inventory:   createInventory(),
+		Partitioner: partition.NewParsedPartitioner(),
+	}
+	context.Inventory.Local.Disks = []*inventory.LocalDisk{
+		{Name: "sdb", Size: 200 * megabyte, Rotational: true},
+		{Name: "nvme0n1", Size: 50 * megabyte, Rotational: false}, // metadata device; rule doesn't select it directly
+	}
+
+	configs := volumeconfig.Set{
+		{Name: "spinners", DiskSelector: volumeconfig.DiskSelector{Rotational: boolPtr(true)},
+			Layout: volumeconfig.LayoutDistributed, MetadataDevice: "nvme0n1"},
+	}
+
+	nextID := 0
+	err = ProvisionVolumeConfigOSDs(context, configs, configDir, func() int {
+		nextID++
+		return nextID
+	})
+	assert.Nil(t, err)
+
+	parsedPartitioner := context.Partitioner.(*partition.ParsedPartitioner)
+
+	// the data disk is zapped and gets only its BLOCK partition
+	assert.Contains(t, parsedPartitioner.ZappedDevices, "sdb")
+	dataSpecs := parsedPartitioner.CreatedSpecs["sdb"]
+	assert.Equal(t, 1, len(dataSpecs))
+	assert.Equal(t, "ROOK-OSD1-BLOCK", dataSpecs[0].Label)
+
+	// the metadata device is zapped separately and gets the WAL+DB partitions
+	assert.Contains(t, parsedPartitioner.ZappedDevices, "nvme0n1")
+	metadataSpecs := parsedPartitioner.CreatedSpecs["nvme0n1"]
+	assert.Equal(t, 2, len(metadataSpecs))
+	assert.Equal(t, "ROOK-OSD1-WAL", metadataSpecs[0].Label)
+	assert.Equal(t, "ROOK-OSD1-DB", metadataSpecs[1].Label)
+}
+
+func TestProvisionVolumeConfigOSDsSkipsUnmatchedDisks(t *testing.T) {
+	context := &clusterd.Context{
+		EtcdClient: util.NewMockEtcdClient(),
+		NodeID:     "node123",
+		Inventory:  createInventory(),
+	}
+	context.Inventory.Local.Disks = []*inventory.LocalDisk{
+		{Name: "sda", Size: 200 * megabyte},
+	}
+
+	// no rules at all, so no disk matches and formatDevice is never reached
+	err := ProvisionVolumeConfigOSDs(context, volumeconfig.Set{}, "/tmp", func() int { return 1 })
+	assert.Nil(t, err)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterd holds the state shared by the services running on a node agent.
+package clusterd
+
+import (
+	"github.com/rook/rook/pkg/cephmgr/osd/partition"
+	"github.com/rook/rook/pkg/clusterd/inventory"
+	"github.com/rook/rook/pkg/util"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// Context holds the dependencies that the node agent's services need in order
+// to interact with the rest of the cluster and the local host.
+type Context struct {
+	EtcdClient util.EtcdClient
+	Executor   exec.Executor
+	NodeID     string
+	ConfigDir  string
+	Inventory  *inventory.Config
+	// Partitioner lays out OSD partitions; when nil, callers fall back to a
+	// partition.SgdiskPartitioner built from Executor.
+	Partitioner partition.DiskPartitioner
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory tracks the hardware discovered on each node in the cluster.
+package inventory
+
+// Config is the inventory known for the cluster, keyed by node.
+type Config struct {
+	Local *LocalNode
+}
+
+// LocalNode is the hardware discovered on the node the agent is running on.
+type LocalNode struct {
+	Disks []*LocalDisk
+}
+
+// LocalDisk describes a single block device discovered on a node.
+type LocalDisk struct {
+	Name       string
+	Size       uint64
+	UUID       string
+	Serial     string
+	Model      string
+	WWN        string
+	ByPathName string
+	Rotational bool
+}
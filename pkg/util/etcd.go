@@ -0,0 +1,48 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// EtcdClient is the minimal key/value interface the cluster services need
+// from the etcd client in order to store desired and discovered state.
+type EtcdClient interface {
+	GetValue(key string) string
+	SetValue(key, value string) error
+	CreateDir(key string) error
+}
+
+// MockEtcdClient is an in-memory EtcdClient used by unit tests.
+type MockEtcdClient struct {
+	values map[string]string
+}
+
+// NewMockEtcdClient creates an empty in-memory etcd client for tests.
+func NewMockEtcdClient() *MockEtcdClient {
+	return &MockEtcdClient{values: map[string]string{}}
+}
+
+func (m *MockEtcdClient) GetValue(key string) string {
+	return m.values[key]
+}
+
+func (m *MockEtcdClient) SetValue(key, value string) error {
+	m.values[key] = value
+	return nil
+}
+
+func (m *MockEtcdClient) CreateDir(key string) error {
+	return nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package exec provides an abstraction over running external commands so that
+// callers can be unit tested without actually shelling out.
+package exec
+
+import (
+	"os/exec"
+)
+
+// Executor runs external commands on behalf of the caller. Implementations
+// exist for shelling out for real and for mocking command execution in tests.
+type Executor interface {
+	ExecuteCommand(actionName string, command string, arg ...string) error
+	ExecuteCommandWithOutput(actionName string, command string, arg ...string) (string, error)
+}
+
+// CommandExecutor is the default Executor that actually runs commands on the host.
+type CommandExecutor struct{}
+
+func (*CommandExecutor) ExecuteCommand(actionName string, command string, arg ...string) error {
+	cmd := exec.Command(command, arg...)
+	return cmd.Run()
+}
+
+func (*CommandExecutor) ExecuteCommandWithOutput(actionName string, command string, arg ...string) (string, error) {
+	cmd := exec.Command(command, arg...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
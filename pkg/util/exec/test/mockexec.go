@@ -0,0 +1,39 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package test provides mock implementations of the exec.Executor interface.
+package test
+
+// MockExecutor is a test double for exec.Executor that lets callers assert on
+// the commands that were run without actually running them.
+type MockExecutor struct {
+	MockExecuteCommand           func(actionName string, command string, args ...string) error
+	MockExecuteCommandWithOutput func(actionName string, command string, args ...string) (string, error)
+}
+
+func (e *MockExecutor) ExecuteCommand(actionName string, command string, arg ...string) error {
+	if e.MockExecuteCommand != nil {
+		return e.MockExecuteCommand(actionName, command, arg...)
+	}
+	return nil
+}
+
+func (e *MockExecutor) ExecuteCommandWithOutput(actionName string, command string, arg ...string) (string, error) {
+	if e.MockExecuteCommandWithOutput != nil {
+		return e.MockExecuteCommandWithOutput(actionName, command, arg...)
+	}
+	return "", nil
+}
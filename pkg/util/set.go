@@ -0,0 +1,42 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// Set is a simple unordered set of strings.
+type Set map[string]bool
+
+// CreateSet builds a Set from the given items.
+func CreateSet(items []string) Set {
+	s := Set{}
+	for _, i := range items {
+		s[i] = true
+	}
+	return s
+}
+
+// Equals returns true if the two sets contain exactly the same items.
+func (s Set) Equals(other Set) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for k := range s {
+		if !other[k] {
+			return false
+		}
+	}
+	return true
+}
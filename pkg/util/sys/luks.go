@@ -0,0 +1,47 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sys
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// LuksUUID returns the LUKS2 UUID of an already-formatted encrypted device.
+// Unlike the device's PARTUUID, this UUID survives a re-encrypt, which is why
+// it's what gets persisted for LUKS2-wrapped partitions.
+func LuksUUID(executor exec.Executor, device string) (uuid.UUID, error) {
+	output, err := executor.ExecuteCommandWithOutput("cryptsetup", "cryptsetup", "luksUUID", device)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get luks uuid for %s: %+v", device, err)
+	}
+	id, err := uuid.Parse(strings.TrimSpace(output))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid luks uuid for %s: %+v", device, err)
+	}
+	return id, nil
+}
+
+// IsLuks returns true if device is already a LUKS container. cryptsetup
+// isLuks exits non-zero for a device that isn't LUKS-formatted; that's an
+// expected outcome here, not an error to report upward.
+func IsLuks(executor exec.Executor, device string) bool {
+	return executor.ExecuteCommand("cryptsetup", "cryptsetup", "isLuks", device) == nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// Partition describes a single entry from `lsblk`, which may be either a
+// whole disk (TYPE "disk") or one of its partitions (TYPE "part").
+type Partition struct {
+	Name      string
+	Size      uint64
+	Type      string
+	Parent    string
+	PartLabel string
+	// PartUUID is the partition's stable PARTUUID, e.g. as read from the GPT
+	// entry. Unlike PartLabel, it survives the disk being rescanned or moved
+	// to another node, so it's preferred for identifying rook-owned
+	// partitions; PartLabel is kept only as a human-readable hint.
+	PartUUID string
+}
+
+// GetDevicePartitions returns the partitions found on the given device along
+// with the disk's own lsblk entry.
+func GetDevicePartitions(device string, executor exec.Executor) (partitions []*Partition, disk *Partition, err error) {
+	output, err := executor.ExecuteCommandWithOutput("lsblk", "lsblk",
+		fmt.Sprintf("/dev/%s", device), "--bytes", "--pairs", "--output", "NAME,SIZE,TYPE,PKNAME,PARTLABEL,PARTUUID")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get partitions for %s: %+v", device, err)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		p := &Partition{}
+		for _, field := range splitQuotedFields(line) {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key, val := parts[0], strings.Trim(parts[1], `"`)
+			switch key {
+			case "NAME":
+				p.Name = val
+			case "SIZE":
+				size, _ := strconv.ParseUint(val, 10, 64)
+				p.Size = size
+			case "TYPE":
+				p.Type = val
+			case "PKNAME":
+				p.Parent = val
+			case "PARTLABEL":
+				p.PartLabel = val
+			case "PARTUUID":
+				p.PartUUID = val
+			}
+		}
+
+		if p.Type == "disk" {
+			disk = p
+			continue
+		}
+		partitions = append(partitions, p)
+	}
+
+	return partitions, disk, nil
+}
+
+// splitQuotedFields splits a line of KEY="value" pairs on whitespace that is
+// outside of quotes, the same format `lsblk --pairs` emits.
+func splitQuotedFields(line string) []string {
+	var fields []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case r == ' ' && !inQuotes:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sys
+
+import (
+	"testing"
+
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDevicePartitionsParsesPartUUID(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutput = func(name string, command string, args ...string) (string, error) {
+		return `NAME="sda" SIZE="65" TYPE="disk" PKNAME="" PARTLABEL="" PARTUUID=""
+NAME="sda1" SIZE="20" TYPE="part" PKNAME="sda" PARTLABEL="ROOK-OSD1-BLOCK" PARTUUID="1f2e3d4c-0000-0000-0000-000000000000"`, nil
+	}
+
+	partitions, disk, err := GetDevicePartitions("sda", executor)
+	assert.Nil(t, err)
+	assert.Equal(t, "sda", disk.Name)
+	assert.Equal(t, 1, len(partitions))
+	assert.Equal(t, "1f2e3d4c-0000-0000-0000-000000000000", partitions[0].PartUUID)
+}